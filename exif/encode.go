@@ -0,0 +1,74 @@
+package exif
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/xor-gate/goexif2/tiff"
+)
+
+// exifAppHeader is the fixed prefix of a JPEG APP1 segment holding exif
+// data.
+var exifAppHeader = []byte("Exif\x00\x00")
+
+// Encode reassembles the decoded (and possibly mutated) exif data -- IFD0,
+// plus the Exif and GPS sub-IFDs if present -- into an APP1 segment payload:
+// the "Exif\0\0" prefix followed by a freshly encoded tiff structure. The
+// bytes after the prefix can be passed back to Decode (which does not accept
+// the prefix itself), letting callers round-trip a file after mutating
+// fields such as Orientation, DateTime or the GPS tags.
+func (x *Exif) Encode(w io.Writer) error {
+	if len(x.Tiff.Dirs) == 0 {
+		return errors.New("exif: cannot encode exif data with no IFD0")
+	}
+
+	var buf bytes.Buffer
+
+	firstIFDOffsetPos, err := tiff.WriteHeader(&buf, x.Tiff.Order, x.Tiff.IsBig)
+	if err != nil {
+		return err
+	}
+
+	ifd0 := tiff.NewIFDBuilderFromDir(x.Tiff.Dirs[0], x.Tiff.Order, x.Tiff.IsBig)
+	if x.exifSubDir != nil {
+		ifd0.SetIFDPointer(exifIFDPointer, 0)
+	}
+	if x.gpsDir != nil {
+		ifd0.SetIFDPointer(gpsIFDPointer, 0)
+	}
+
+	ifd0Offset := int64(buf.Len())
+	_, valuePos, err := ifd0.EncodeIFD(&buf)
+	if err != nil {
+		return err
+	}
+
+	subOffsets := make(map[uint16]int64)
+	if x.exifSubDir != nil {
+		b := tiff.NewIFDBuilderFromDir(x.exifSubDir, x.Tiff.Order, x.Tiff.IsBig)
+		subOffsets[exifIFDPointer] = int64(buf.Len())
+		if _, _, err := b.EncodeIFD(&buf); err != nil {
+			return err
+		}
+	}
+	if x.gpsDir != nil {
+		b := tiff.NewIFDBuilderFromDir(x.gpsDir, x.Tiff.Order, x.Tiff.IsBig)
+		subOffsets[gpsIFDPointer] = int64(buf.Len())
+		if _, _, err := b.EncodeIFD(&buf); err != nil {
+			return err
+		}
+	}
+
+	out := buf.Bytes()
+	tiff.PatchOffset(out, x.Tiff.Order, x.Tiff.IsBig, firstIFDOffsetPos, ifd0Offset)
+	for field, offset := range subOffsets {
+		tiff.PatchOffset(out, x.Tiff.Order, x.Tiff.IsBig, valuePos[field], offset)
+	}
+
+	if _, err := w.Write(exifAppHeader); err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
@@ -0,0 +1,47 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/xor-gate/goexif2/tiff"
+)
+
+// TestEncodeBigTIFFGPSPointerRoundTrip checks that the GPSInfo sub-IFD
+// pointer Encode writes into IFD0 is readable back as a BigTIFF 8-byte
+// offset, not cut to the first 4 bytes of its 8-byte inline slot (the
+// all-zero high bytes, in big-endian order) by a plain DTLong value.
+func TestEncodeBigTIFFGPSPointerRoundTrip(t *testing.T) {
+	order := binary.BigEndian
+
+	x := &Exif{
+		Tiff: &tiff.Tiff{
+			Order: order,
+			IsBig: true,
+			Dirs: []*tiff.Dir{
+				{Tags: []*tiff.Tag{{FieldNum: 0x0112, Type: tiff.DTShort, Count: 1, Val: []byte{0x00, 0x01}, Order: order}}},
+			},
+		},
+		main: make(map[FieldName]*tiff.Tag),
+	}
+	x.gpsDir = &tiff.Dir{
+		Tags: []*tiff.Tag{{FieldNum: 0x0000, Type: tiff.DTByte, Count: 2, Val: []byte{2, 3}, Order: order}}, // GPSVersionID
+	}
+
+	var buf bytes.Buffer
+	if err := x.Encode(&buf); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	x2, err := Decode(bytes.NewReader(buf.Bytes()[len(exifPrefix):]))
+	if err != nil {
+		t.Fatalf("Decode of encoded data: %v", err)
+	}
+	if x2.gpsDir == nil {
+		t.Fatal("GPS sub-IFD was not found after round trip (pointer decoded as 0)")
+	}
+	if len(x2.gpsDir.Tags) != 1 || x2.gpsDir.Tags[0].FieldNum != 0x0000 {
+		t.Errorf("got GPS dir %+v, want GPSVersionID tag", x2.gpsDir.Tags)
+	}
+}
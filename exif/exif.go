@@ -0,0 +1,238 @@
+// Package exif implements decoding of EXIF data as defined in the EXIF 2.2
+// specification, built on top of the tiff package.
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+
+	"github.com/xor-gate/goexif2/tiff"
+)
+
+// exifPrefix is the marker that precedes tiff-encoded exif data inside a
+// JPEG APP1 segment.
+var exifPrefix = []byte("Exif\x00\x00")
+
+// FieldName is the name of an exif field, e.g. "DateTimeOriginal".
+type FieldName string
+
+// Known exif and GPS field names.
+const (
+	Orientation      FieldName = "Orientation"
+	DateTime         FieldName = "DateTime"
+	DateTimeOriginal FieldName = "DateTimeOriginal"
+
+	GPSLatitude     FieldName = "GPSLatitude"
+	GPSLatitudeRef  FieldName = "GPSLatitudeRef"
+	GPSLongitude    FieldName = "GPSLongitude"
+	GPSLongitudeRef FieldName = "GPSLongitudeRef"
+	GPSAltitude     FieldName = "GPSAltitude"
+	GPSAltitudeRef  FieldName = "GPSAltitudeRef"
+	GPSTimeStamp    FieldName = "GPSTimeStamp"
+	GPSDateStamp    FieldName = "GPSDateStamp"
+)
+
+// exifIFDPointer and gpsIFDPointer are the IFD0 tags that point at the Exif
+// and GPS sub-IFDs, respectively.
+const (
+	exifIFDPointer = 0x8769
+	gpsIFDPointer  = 0x8825
+)
+
+// ifd0Fields maps the tags this package understands in IFD0.
+var ifd0Fields = map[uint16]FieldName{
+	0x0112: Orientation,
+	0x0132: DateTime,
+}
+
+// exifSubIFDFields maps the tags this package understands in the Exif
+// sub-IFD.
+var exifSubIFDFields = map[uint16]FieldName{
+	0x9003: DateTimeOriginal,
+}
+
+// gpsIFDFields maps the tags this package understands in the GPS sub-IFD.
+var gpsIFDFields = map[uint16]FieldName{
+	0x0001: GPSLatitudeRef,
+	0x0002: GPSLatitude,
+	0x0003: GPSLongitudeRef,
+	0x0004: GPSLongitude,
+	0x0005: GPSAltitudeRef,
+	0x0006: GPSAltitude,
+	0x0007: GPSTimeStamp,
+	0x001d: GPSDateStamp,
+}
+
+// ErrFieldNotFound is returned by Get when the requested field wasn't
+// present in the decoded exif data.
+var ErrFieldNotFound = errors.New("exif: field not found")
+
+// Exif provides access to decoded EXIF metadata.
+type Exif struct {
+	// Tiff is the underlying tiff structure the exif data was parsed from.
+	// IFD0 is Tiff.Dirs[0].
+	Tiff *tiff.Tiff
+
+	main       map[FieldName]*tiff.Tag
+	exifSubDir *tiff.Dir
+	gpsDir     *tiff.Dir
+}
+
+// Decode parses EXIF data from r, which may be either a full JPEG file (in
+// which case the APP1 Exif segment is located automatically) or raw
+// tiff-encoded exif data.
+//
+// Decode applies tiff.DefaultDecoderOptions; use DecodeWithOptions when r
+// may come from an untrusted source and tighter bounds are wanted.
+func Decode(r io.Reader) (*Exif, error) {
+	return DecodeWithOptions(r, tiff.DefaultDecoderOptions)
+}
+
+// DecodeWithOptions is Decode with explicit tiff.DecoderOptions, forwarded
+// to the underlying tiff decode and used to bound how many levels of
+// sub-IFD (Exif, GPS) this package will descend into.
+func DecodeWithOptions(r io.Reader, opts tiff.DecoderOptions) (*Exif, error) {
+	raw, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := raw
+	if len(raw) >= 2 && raw[0] == 0xff && raw[1] == 0xd8 {
+		payload, err = findEXIFInJPEG(raw)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tif, err := tiff.DecodeWithOptions(bytes.NewReader(payload), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	x := &Exif{Tiff: tif, main: make(map[FieldName]*tiff.Tag)}
+	x.loadTags()
+
+	if err := x.loadSubIFDs(bytes.NewReader(payload), opts); err != nil {
+		return nil, err
+	}
+
+	return x, nil
+}
+
+// findEXIFInJPEG scans the JPEG segments in raw for an APP1 segment holding
+// Exif data and returns its tiff-encoded payload.
+func findEXIFInJPEG(raw []byte) ([]byte, error) {
+	pos := 2 // skip SOI
+	for pos+4 <= len(raw) {
+		if raw[pos] != 0xff {
+			return nil, errors.New("exif: malformed jpeg, expected marker")
+		}
+		marker := raw[pos+1]
+		if marker == 0xd8 || marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7) {
+			pos += 2
+			continue
+		}
+		if marker == 0xda { // start of scan: no more markers to scan
+			break
+		}
+		segLen := int(binary.BigEndian.Uint16(raw[pos+2:]))
+		segStart := pos + 4
+		segEnd := pos + 2 + segLen
+		if segEnd > len(raw) {
+			return nil, errors.New("exif: malformed jpeg, segment runs past end of file")
+		}
+		if marker == 0xe1 && bytes.HasPrefix(raw[segStart:segEnd], exifPrefix) {
+			return raw[segStart+len(exifPrefix) : segEnd], nil
+		}
+		pos = segEnd
+	}
+	return nil, errors.New("exif: no exif data found")
+}
+
+func (x *Exif) loadTags() {
+	if len(x.Tiff.Dirs) == 0 {
+		return
+	}
+	for _, t := range x.Tiff.Dirs[0].Tags {
+		if name, ok := ifd0Fields[t.FieldNum]; ok {
+			x.main[name] = t
+		}
+	}
+}
+
+// loadSubIFDs reads the Exif and GPS sub-IFDs pointed to from IFD0, if
+// opts.MaxRecursionInSubIFDs allows descending at least one level (both are
+// direct children of IFD0, so a limit of 0 skips them entirely).
+func (x *Exif) loadSubIFDs(r tiff.ReadAtReaderSeeker, opts tiff.DecoderOptions) error {
+	if len(x.Tiff.Dirs) == 0 {
+		return nil
+	}
+	if opts.MaxRecursionInSubIFDs == 0 {
+		return nil
+	}
+	ifd0 := x.Tiff.Dirs[0]
+
+	if err := x.loadSubIFD(r, ifd0, exifIFDPointer, exifSubIFDFields, opts); err != nil {
+		return err
+	}
+	if err := x.loadSubIFD(r, ifd0, gpsIFDPointer, gpsIFDFields, opts); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (x *Exif) loadSubIFD(r tiff.ReadAtReaderSeeker, ifd0 *tiff.Dir, pointerTag uint16, fields map[uint16]FieldName, opts tiff.DecoderOptions) error {
+	var offset int64
+	for _, t := range ifd0.Tags {
+		if t.FieldNum == pointerTag {
+			v, err := t.Int(0)
+			if err != nil {
+				// Malformed pointer tag; nothing sane to follow.
+				return nil
+			}
+			offset = v
+			break
+		}
+	}
+	if offset == 0 {
+		return nil
+	}
+
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	d, _, err := tiff.DecodeDirWithOptions(r, x.Tiff.Order, x.Tiff.IsBig, opts, size)
+	if err != nil {
+		return err
+	}
+	for _, t := range d.Tags {
+		if name, ok := fields[t.FieldNum]; ok {
+			x.main[name] = t
+		}
+	}
+
+	switch pointerTag {
+	case exifIFDPointer:
+		x.exifSubDir = d
+	case gpsIFDPointer:
+		x.gpsDir = d
+	}
+	return nil
+}
+
+// Get returns the tag for the named field, or ErrFieldNotFound if it wasn't
+// present in the decoded data.
+func (x *Exif) Get(name FieldName) (*tiff.Tag, error) {
+	if t, ok := x.main[name]; ok {
+		return t, nil
+	}
+	return nil, ErrFieldNotFound
+}
@@ -0,0 +1,251 @@
+package exif
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/xor-gate/goexif2/tiff"
+)
+
+// gpsFieldNum maps the GPS field names this package understands back to
+// their tag number within the GPS sub-IFD, for use by the setters below.
+var gpsFieldNum = map[FieldName]uint16{
+	GPSLatitudeRef:  0x0001,
+	GPSLatitude:     0x0002,
+	GPSLongitudeRef: 0x0003,
+	GPSLongitude:    0x0004,
+	GPSAltitudeRef:  0x0005,
+	GPSAltitude:     0x0006,
+	GPSTimeStamp:    0x0007,
+	GPSDateStamp:    0x001d,
+}
+
+// dmsToDeg converts a GPS tag holding three DTRational values (degrees,
+// minutes, seconds) into decimal degrees.
+func dmsToDeg(t *tiff.Tag) (float64, error) {
+	var deg float64
+	for i, scale := range [3]float64{1, 1.0 / 60, 1.0 / 3600} {
+		num, denom, err := t.Rat2(i)
+		if err != nil {
+			return 0, err
+		}
+		if denom == 0 {
+			return 0, errors.New("exif: zero denominator in GPS rational")
+		}
+		deg += (float64(num) / float64(denom)) * scale
+	}
+	return deg, nil
+}
+
+// LatLong returns the GPS latitude and longitude, in signed decimal
+// degrees (positive north/east, negative south/west).
+func (x *Exif) LatLong() (lat, lng float64, err error) {
+	latTag, err := x.Get(GPSLatitude)
+	if err != nil {
+		return 0, 0, err
+	}
+	latRefTag, err := x.Get(GPSLatitudeRef)
+	if err != nil {
+		return 0, 0, err
+	}
+	lngTag, err := x.Get(GPSLongitude)
+	if err != nil {
+		return 0, 0, err
+	}
+	lngRefTag, err := x.Get(GPSLongitudeRef)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	lat, err = dmsToDeg(latTag)
+	if err != nil {
+		return 0, 0, err
+	}
+	lng, err = dmsToDeg(lngTag)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	latRef, err := latRefTag.StringVal()
+	if err != nil {
+		return 0, 0, err
+	}
+	if latRef == "S" {
+		lat = -lat
+	}
+	lngRef, err := lngRefTag.StringVal()
+	if err != nil {
+		return 0, 0, err
+	}
+	if lngRef == "W" {
+		lng = -lng
+	}
+
+	return lat, lng, nil
+}
+
+// Altitude returns the GPS altitude in meters above sea level (negative if
+// GPSAltitudeRef indicates below sea level).
+func (x *Exif) Altitude() (meters float64, err error) {
+	altTag, err := x.Get(GPSAltitude)
+	if err != nil {
+		return 0, err
+	}
+	num, denom, err := altTag.Rat2(0)
+	if err != nil {
+		return 0, err
+	}
+	if denom == 0 {
+		return 0, errors.New("exif: zero denominator in GPSAltitude")
+	}
+	meters = float64(num) / float64(denom)
+
+	if refTag, err := x.Get(GPSAltitudeRef); err == nil {
+		ref, err := refTag.Int(0)
+		if err == nil && ref == 1 {
+			meters = -meters
+		}
+	}
+
+	return meters, nil
+}
+
+// GPSTime combines GPSDateStamp and GPSTimeStamp into a UTC time.Time.
+func (x *Exif) GPSTime() (time.Time, error) {
+	dateTag, err := x.Get(GPSDateStamp)
+	if err != nil {
+		return time.Time{}, err
+	}
+	date, err := dateTag.StringVal()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	timeTag, err := x.Get(GPSTimeStamp)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var hms [3]float64
+	for i := range hms {
+		num, denom, err := timeTag.Rat2(i)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if denom == 0 {
+			return time.Time{}, errors.New("exif: zero denominator in GPSTimeStamp")
+		}
+		hms[i] = float64(num) / float64(denom)
+	}
+
+	d, err := time.Parse("2006:01:02", date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("exif: invalid GPSDateStamp: %w", err)
+	}
+
+	sec := hms[2]
+	whole := int(sec)
+	nsec := int64((sec - float64(whole)) * 1e9)
+
+	return time.Date(d.Year(), d.Month(), d.Day(), int(hms[0]), int(hms[1]), whole, int(nsec), time.UTC), nil
+}
+
+// degToDMSRationals converts non-negative decimal degrees into the
+// degrees/minutes/seconds rational triple used by GPS tags, with seconds
+// scaled by denom for fractional precision.
+func degToDMSRationals(deg float64, denom uint32) [][2]uint32 {
+	d := uint32(deg)
+	minFloat := (deg - float64(d)) * 60
+	m := uint32(minFloat)
+	s := (minFloat - float64(m)) * 60
+
+	return [][2]uint32{
+		{d, 1},
+		{m, 1},
+		{uint32(s * float64(denom)), denom},
+	}
+}
+
+const secDenom = 1000
+
+// gpsDirBuilder returns an IFDBuilder seeded from the existing GPS sub-IFD,
+// or an empty one if none was present in the decoded data.
+func (x *Exif) gpsDirBuilder() *tiff.IFDBuilder {
+	if x.gpsDir == nil {
+		x.gpsDir = &tiff.Dir{}
+	}
+	return tiff.NewIFDBuilderFromDir(x.gpsDir, x.Tiff.Order, x.Tiff.IsBig)
+}
+
+// commitGPSDir replaces x.gpsDir with all of b's tags -- not just the ones
+// this package knows how to name -- so tags seeded from the decoded GPS
+// sub-IFD (GPSVersionID, GPSMapDatum, GPSDOP, ...) survive a mutation they
+// weren't involved in, and refreshes x.main for the GPS fields this package
+// does recognize so subsequent Get calls see the new values.
+func (x *Exif) commitGPSDir(b *tiff.IFDBuilder) {
+	d := &tiff.Dir{Tags: b.Tags(), RawTags: b.RawTags()}
+	for name, num := range gpsFieldNum {
+		if t, ok := b.Tag(num); ok {
+			x.main[name] = t
+		}
+	}
+	x.gpsDir = d
+}
+
+// SetLatLong sets the GPS latitude and longitude, in signed decimal
+// degrees, replacing any existing GPS position.
+func (x *Exif) SetLatLong(lat, lng float64) {
+	b := x.gpsDirBuilder()
+
+	latRef := "N"
+	if lat < 0 {
+		latRef = "S"
+		lat = -lat
+	}
+	lngRef := "E"
+	if lng < 0 {
+		lngRef = "W"
+		lng = -lng
+	}
+
+	b.SetASCII(gpsFieldNum[GPSLatitudeRef], latRef)
+	b.SetRationals(gpsFieldNum[GPSLatitude], degToDMSRationals(lat, secDenom))
+	b.SetASCII(gpsFieldNum[GPSLongitudeRef], lngRef)
+	b.SetRationals(gpsFieldNum[GPSLongitude], degToDMSRationals(lng, secDenom))
+
+	x.commitGPSDir(b)
+}
+
+// SetAltitude sets the GPS altitude, in meters above sea level (a negative
+// value is recorded as below sea level via GPSAltitudeRef).
+func (x *Exif) SetAltitude(meters float64) {
+	b := x.gpsDirBuilder()
+
+	ref := byte(0)
+	if meters < 0 {
+		ref = 1
+		meters = -meters
+	}
+
+	b.SetByte(gpsFieldNum[GPSAltitudeRef], ref)
+	b.SetRational(gpsFieldNum[GPSAltitude], uint32(meters*secDenom), secDenom)
+
+	x.commitGPSDir(b)
+}
+
+// SetGPSTime sets GPSDateStamp and GPSTimeStamp from t, which is converted
+// to UTC.
+func (x *Exif) SetGPSTime(t time.Time) {
+	t = t.UTC()
+	b := x.gpsDirBuilder()
+
+	b.SetASCII(gpsFieldNum[GPSDateStamp], t.Format("2006:01:02"))
+	b.SetRationals(gpsFieldNum[GPSTimeStamp], [][2]uint32{
+		{uint32(t.Hour()), 1},
+		{uint32(t.Minute()), 1},
+		{uint32(t.Second())*secDenom + uint32(t.Nanosecond())/(1e9/secDenom), secDenom},
+	})
+
+	x.commitGPSDir(b)
+}
@@ -0,0 +1,125 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/xor-gate/goexif2/tiff"
+)
+
+func newTestExif(t *testing.T) *Exif {
+	order := binary.LittleEndian
+	b := tiff.NewIFDBuilder(order, false)
+	b.SetShort(0x0112, 1) // Orientation, just to have a non-empty IFD0
+
+	var buf bytes.Buffer
+	pos, err := tiff.WriteHeader(&buf, order, false)
+	if err != nil {
+		t.Fatalf("WriteHeader: %v", err)
+	}
+	ifdOffset := int64(buf.Len())
+	if _, _, err := b.EncodeIFD(&buf); err != nil {
+		t.Fatalf("EncodeIFD: %v", err)
+	}
+	out := buf.Bytes()
+	tiff.PatchOffset(out, order, false, pos, ifdOffset)
+
+	x, err := Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	return x
+}
+
+func roundTrip(t *testing.T, x *Exif) *Exif {
+	var buf bytes.Buffer
+	if err := x.Encode(&buf); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	out, err := Decode(bytes.NewReader(buf.Bytes()[len(exifPrefix):]))
+	if err != nil {
+		t.Fatalf("Decode of encoded data: %v", err)
+	}
+	return out
+}
+
+func TestLatLongRoundTrip(t *testing.T) {
+	x := newTestExif(t)
+	x.SetLatLong(37.7749, -122.4194)
+
+	x2 := roundTrip(t, x)
+	lat, lng, err := x2.LatLong()
+	if err != nil {
+		t.Fatalf("LatLong: %v", err)
+	}
+	if math.Abs(lat-37.7749) > 1e-4 || math.Abs(lng+122.4194) > 1e-4 {
+		t.Errorf("got lat=%v lng=%v, want approx 37.7749,-122.4194", lat, lng)
+	}
+}
+
+func TestAltitudeRoundTrip(t *testing.T) {
+	x := newTestExif(t)
+	x.SetAltitude(-12.5)
+
+	x2 := roundTrip(t, x)
+	alt, err := x2.Altitude()
+	if err != nil {
+		t.Fatalf("Altitude: %v", err)
+	}
+	if math.Abs(alt+12.5) > 1e-6 {
+		t.Errorf("got altitude=%v, want -12.5", alt)
+	}
+}
+
+func TestSetLatLongPreservesOtherGPSTags(t *testing.T) {
+	x := newTestExif(t)
+
+	// Seed the GPS sub-IFD as if it had already been decoded with tags this
+	// package has no setter for, plus one of an unrecognized type.
+	order := binary.LittleEndian
+	x.gpsDir = &tiff.Dir{
+		Tags: []*tiff.Tag{
+			{FieldNum: 0x0000, Type: tiff.DTByte, Count: 2, Val: []byte{2, 3}, Order: order}, // GPSVersionID
+		},
+		RawTags: []*tiff.RawTag{
+			{FieldNum: 0x0012, Type: 0xffff, Count: 4, Raw: []byte{0xde, 0xad, 0xbe, 0xef}}, // GPSMapDatum, bogus type
+		},
+	}
+
+	x.SetLatLong(37.7749, -122.4194)
+
+	x2 := roundTrip(t, x)
+	if len(x2.gpsDir.Tags) == 0 {
+		t.Fatal("GPSVersionID was dropped by SetLatLong")
+	}
+	foundVersion := false
+	for _, tag := range x2.gpsDir.Tags {
+		if tag.FieldNum == 0x0000 {
+			foundVersion = true
+		}
+	}
+	if !foundVersion {
+		t.Error("GPSVersionID was dropped by SetLatLong")
+	}
+	if len(x2.gpsDir.RawTags) != 1 || x2.gpsDir.RawTags[0].FieldNum != 0x0012 {
+		t.Errorf("GPSMapDatum RawTag was dropped by SetLatLong, got RawTags=%+v", x2.gpsDir.RawTags)
+	}
+}
+
+func TestGPSTimeRoundTrip(t *testing.T) {
+	x := newTestExif(t)
+	want := time.Date(2026, 7, 26, 13, 45, 30, 0, time.UTC)
+	x.SetGPSTime(want)
+
+	x2 := roundTrip(t, x)
+	got, err := x2.GPSTime()
+	if err != nil {
+		t.Fatalf("GPSTime: %v", err)
+	}
+	if !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,279 @@
+package exif
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+
+	"github.com/xor-gate/goexif2/tiff"
+)
+
+// xmpPrefix identifies an XMP packet embedded in a JPEG APP1 segment.
+var xmpPrefix = []byte("http://ns.adobe.com/xap/")
+
+const (
+	markerSOI   = 0xd8
+	markerSOS   = 0xda
+	markerAPP1  = 0xe1
+	markerAPP13 = 0xed
+)
+
+// ScrubOptions controls how Terminate/Strip rewrite metadata.
+type ScrubOptions struct {
+	// KeepOrientation, if true, re-inserts a minimal JPEG APP1 segment
+	// containing only the Orientation tag instead of dropping Exif data
+	// entirely, so viewers still rotate the image correctly. It has no
+	// effect on PNG input: no eXIf chunk is written back.
+	KeepOrientation bool
+}
+
+// Terminate copies src to dst, with all EXIF, XMP, IPTC and Photoshop
+// metadata segments/chunks removed, without ever decoding the image body.
+// format must be "jpeg" or "png".
+func Terminate(dst io.Writer, src io.Reader, format string) error {
+	return TerminateWithOptions(dst, src, format, ScrubOptions{})
+}
+
+// TerminateWithOptions behaves like Terminate but accepts ScrubOptions.
+func TerminateWithOptions(dst io.Writer, src io.Reader, format string, opts ScrubOptions) error {
+	switch format {
+	case "jpeg":
+		return scrubJPEG(dst, bufio.NewReader(src), opts)
+	case "png":
+		return scrubPNG(dst, bufio.NewReader(src), opts)
+	}
+	return errors.New("exif: unsupported format " + format)
+}
+
+// Strip is a byte-slice convenience wrapper around Terminate.
+func Strip(data []byte, format string) ([]byte, error) {
+	return StripWithOptions(data, format, ScrubOptions{})
+}
+
+// StripWithOptions is a byte-slice convenience wrapper around
+// TerminateWithOptions.
+func StripWithOptions(data []byte, format string, opts ScrubOptions) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := TerminateWithOptions(&buf, bytes.NewReader(data), format, opts); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func scrubJPEG(dst io.Writer, src *bufio.Reader, opts ScrubOptions) error {
+	soi := make([]byte, 2)
+	if _, err := io.ReadFull(src, soi); err != nil {
+		return errors.New("exif: could not read jpeg SOI: " + err.Error())
+	}
+	if soi[0] != 0xff || soi[1] != markerSOI {
+		return errors.New("exif: not a jpeg file")
+	}
+	if _, err := dst.Write(soi); err != nil {
+		return err
+	}
+
+	var orientation *tiff.Tag
+
+	for {
+		marker := make([]byte, 2)
+		if _, err := io.ReadFull(src, marker); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if marker[0] != 0xff {
+			return errors.New("exif: malformed jpeg, expected marker")
+		}
+		code := marker[1]
+
+		// Markers with no payload: standalone restart markers and TEM.
+		if code == 0x01 || (code >= 0xd0 && code <= 0xd7) {
+			if _, err := dst.Write(marker); err != nil {
+				return err
+			}
+			continue
+		}
+
+		lenBuf := make([]byte, 2)
+		if _, err := io.ReadFull(src, lenBuf); err != nil {
+			return err
+		}
+		segLen := int(binary.BigEndian.Uint16(lenBuf))
+		if segLen < 2 {
+			return errors.New("exif: malformed jpeg segment length")
+		}
+		payload := make([]byte, segLen-2)
+		if _, err := io.ReadFull(src, payload); err != nil {
+			return err
+		}
+
+		isExif := code == markerAPP1 && bytes.HasPrefix(payload, exifPrefix)
+		isXMP := code == markerAPP1 && bytes.HasPrefix(payload, xmpPrefix)
+		isPhotoshop := code == markerAPP13
+
+		if isExif && opts.KeepOrientation && orientation == nil {
+			if x, err := decodeTiffPayload(payload[len(exifPrefix):]); err == nil {
+				if o, err := x.Get(Orientation); err == nil {
+					orientation = o
+					if err := writeOrientationSegment(dst, orientation); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		if isExif || isXMP || isPhotoshop {
+			continue
+		}
+
+		if _, err := dst.Write(marker); err != nil {
+			return err
+		}
+		if _, err := dst.Write(lenBuf); err != nil {
+			return err
+		}
+		if _, err := dst.Write(payload); err != nil {
+			return err
+		}
+
+		if code == markerSOS {
+			// Entropy-coded scan data follows; copy it byte-for-byte
+			// through to EOI (and any trailer) without interpretation.
+			_, err := io.Copy(dst, src)
+			return err
+		}
+	}
+}
+
+// decodeTiffPayload is a small helper around Decode for re-parsing the raw
+// tiff bytes of an already-located APP1 segment.
+func decodeTiffPayload(tiffBytes []byte) (*Exif, error) {
+	tif, err := tiff.Decode(bytes.NewReader(tiffBytes))
+	if err != nil {
+		return nil, err
+	}
+	x := &Exif{Tiff: tif, main: make(map[FieldName]*tiff.Tag)}
+	x.loadTags()
+	return x, nil
+}
+
+// writeOrientationSegment emits a minimal APP1/Exif segment containing only
+// the Orientation tag.
+func writeOrientationSegment(dst io.Writer, orientation *tiff.Tag) error {
+	order := binary.BigEndian
+	b := tiff.NewIFDBuilder(order, false)
+	v, err := orientation.Int(0)
+	if err != nil {
+		return err
+	}
+	b.SetShort(0x0112, uint16(v))
+
+	var tiffBuf bytes.Buffer
+	pos, err := tiff.WriteHeader(&tiffBuf, order, false)
+	if err != nil {
+		return err
+	}
+	ifdOffset := int64(tiffBuf.Len())
+	if _, _, err := b.EncodeIFD(&tiffBuf); err != nil {
+		return err
+	}
+	out := tiffBuf.Bytes()
+	tiff.PatchOffset(out, order, false, pos, ifdOffset)
+
+	payload := append(append([]byte{}, exifPrefix...), out...)
+
+	if _, err := dst.Write([]byte{0xff, markerAPP1}); err != nil {
+		return err
+	}
+	lenBuf := make([]byte, 2)
+	binary.BigEndian.PutUint16(lenBuf, uint16(len(payload)+2))
+	if _, err := dst.Write(lenBuf); err != nil {
+		return err
+	}
+	_, err = dst.Write(payload)
+	return err
+}
+
+// pngMetadataChunks are the PNG chunk types this package treats as
+// metadata and strips.
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"iTXt": true,
+	"zTXt": true,
+}
+
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+func scrubPNG(dst io.Writer, src *bufio.Reader, opts ScrubOptions) error {
+	sig := make([]byte, 8)
+	if _, err := io.ReadFull(src, sig); err != nil {
+		return errors.New("exif: could not read png signature: " + err.Error())
+	}
+	if !bytes.Equal(sig, pngSignature) {
+		return errors.New("exif: not a png file")
+	}
+	if _, err := dst.Write(sig); err != nil {
+		return err
+	}
+
+	for {
+		lenBuf := make([]byte, 4)
+		if _, err := io.ReadFull(src, lenBuf); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		length := binary.BigEndian.Uint32(lenBuf)
+
+		typeBuf := make([]byte, 4)
+		if _, err := io.ReadFull(src, typeBuf); err != nil {
+			return err
+		}
+
+		data := make([]byte, length)
+		if _, err := io.ReadFull(src, data); err != nil {
+			return err
+		}
+
+		crcBuf := make([]byte, 4)
+		if _, err := io.ReadFull(src, crcBuf); err != nil {
+			return err
+		}
+
+		if pngMetadataChunks[string(typeBuf)] {
+			continue
+		}
+
+		if err := writePNGChunk(dst, typeBuf, data); err != nil {
+			return err
+		}
+	}
+}
+
+func writePNGChunk(dst io.Writer, typ, data []byte) error {
+	lenBuf := make([]byte, 4)
+	binary.BigEndian.PutUint32(lenBuf, uint32(len(data)))
+	if _, err := dst.Write(lenBuf); err != nil {
+		return err
+	}
+	if _, err := dst.Write(typ); err != nil {
+		return err
+	}
+	if _, err := dst.Write(data); err != nil {
+		return err
+	}
+
+	crc := crc32.NewIEEE()
+	crc.Write(typ)
+	crc.Write(data)
+	crcBuf := make([]byte, 4)
+	binary.BigEndian.PutUint32(crcBuf, crc.Sum32())
+	_, err := dst.Write(crcBuf)
+	return err
+}
@@ -0,0 +1,97 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+)
+
+func jpegAPP1(payload []byte) []byte {
+	var buf bytes.Buffer
+	buf.Write([]byte{0xff, 0xd8})
+	buf.Write([]byte{0xff, markerAPP1})
+	l := make([]byte, 2)
+	binary.BigEndian.PutUint16(l, uint16(len(payload)+2))
+	buf.Write(l)
+	buf.Write(payload)
+	buf.Write([]byte{0xff, markerSOS, 0x00, 0x02})
+	buf.Write([]byte{0x11, 0x22, 0x33})
+	buf.Write([]byte{0xff, 0xd9})
+	return buf.Bytes()
+}
+
+func TestStripJPEGRemovesExif(t *testing.T) {
+	tiffBytes := []byte("II*\x00\x08\x00\x00\x00\x01\x00\x12\x01\x03\x00\x01\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00")
+	in := jpegAPP1(append([]byte("Exif\x00\x00"), tiffBytes...))
+
+	out, err := Strip(in, "jpeg")
+	if err != nil {
+		t.Fatalf("Strip returned error: %v", err)
+	}
+	if bytes.Contains(out, []byte("Exif")) {
+		t.Errorf("expected Exif marker to be stripped, got % x", out)
+	}
+	if !bytes.HasPrefix(out, []byte{0xff, 0xd8}) || !bytes.HasSuffix(out, []byte{0xff, 0xd9}) {
+		t.Errorf("expected SOI/EOI to be preserved, got % x", out)
+	}
+}
+
+func TestStripJPEGKeepOrientation(t *testing.T) {
+	tiffBytes := []byte("II*\x00\x08\x00\x00\x00\x01\x00\x12\x01\x03\x00\x01\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00")
+	in := jpegAPP1(append([]byte("Exif\x00\x00"), tiffBytes...))
+
+	out, err := StripWithOptions(in, "jpeg", ScrubOptions{KeepOrientation: true})
+	if err != nil {
+		t.Fatalf("StripWithOptions returned error: %v", err)
+	}
+
+	x, err := Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("could not decode stripped jpeg: %v", err)
+	}
+	tag, err := x.Get(Orientation)
+	if err != nil {
+		t.Fatalf("expected Orientation to survive, got error: %v", err)
+	}
+	v, err := tag.Int(0)
+	if err != nil || v != 1 {
+		t.Errorf("expected Orientation=1, got %d (err %v)", v, err)
+	}
+}
+
+func pngChunk(typ string, data []byte) []byte {
+	var buf bytes.Buffer
+	l := make([]byte, 4)
+	binary.BigEndian.PutUint32(l, uint32(len(data)))
+	buf.Write(l)
+	buf.WriteString(typ)
+	buf.Write(data)
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(typ))
+	crc.Write(data)
+	c := make([]byte, 4)
+	binary.BigEndian.PutUint32(c, crc.Sum32())
+	buf.Write(c)
+	return buf.Bytes()
+}
+
+func TestStripPNGRemovesTextChunks(t *testing.T) {
+	var buf bytes.Buffer
+	buf.Write(pngSignature)
+	buf.Write(pngChunk("IHDR", make([]byte, 13)))
+	buf.Write(pngChunk("tEXt", []byte("comment")))
+	buf.Write(pngChunk("IDAT", []byte("fakeimagedata")))
+	buf.Write(pngChunk("IEND", nil))
+
+	out, err := Strip(buf.Bytes(), "png")
+	if err != nil {
+		t.Fatalf("Strip returned error: %v", err)
+	}
+	if bytes.Contains(out, []byte("tEXt")) {
+		t.Errorf("expected tEXt chunk to be stripped, got % x", out)
+	}
+	if !bytes.Contains(out, []byte("IDAT")) {
+		t.Errorf("expected IDAT chunk to survive, got % x", out)
+	}
+}
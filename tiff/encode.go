@@ -0,0 +1,380 @@
+package tiff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"sort"
+)
+
+// IFDBuilder lets callers construct an Image File Directory in memory, to be
+// serialized by EncodeIFD ((*Tiff).Encode builds one per Dir and serializes
+// it the same way). Unlike Dir, which only holds decoded tags,
+// IFDBuilder exposes typed setters so tags can be created, overwritten or
+// removed before encoding.
+type IFDBuilder struct {
+	order   binary.ByteOrder
+	isBig   bool
+	tags    map[uint16]*Tag
+	rawTags map[uint16]*RawTag
+}
+
+// NewIFDBuilder returns an empty IFDBuilder for a tiff using the given byte
+// order. isBig selects 32 vs. 64 bit IFD offsets (BigTIFF).
+func NewIFDBuilder(order binary.ByteOrder, isBig bool) *IFDBuilder {
+	return &IFDBuilder{
+		order:   order,
+		isBig:   isBig,
+		tags:    make(map[uint16]*Tag),
+		rawTags: make(map[uint16]*RawTag),
+	}
+}
+
+// NewIFDBuilderFromDir seeds an IFDBuilder with the tags already present in
+// a decoded Dir, so callers can mutate an existing IFD rather than build one
+// from scratch. This includes d.RawTags, so tags of a type NewIFDBuilder's
+// caller doesn't recognize still survive an encode.
+func NewIFDBuilderFromDir(d *Dir, order binary.ByteOrder, isBig bool) *IFDBuilder {
+	b := NewIFDBuilder(order, isBig)
+	for _, t := range d.Tags {
+		cp := *t
+		b.tags[t.FieldNum] = &cp
+	}
+	for _, raw := range d.RawTags {
+		cp := *raw
+		b.rawTags[raw.FieldNum] = &cp
+	}
+	return b
+}
+
+func (b *IFDBuilder) set(field, typ uint16, count uint32, val []byte) {
+	b.tags[field] = &Tag{
+		FieldNum: field,
+		Type:     typ,
+		Count:    count,
+		Val:      val,
+		Order:    b.order,
+	}
+}
+
+// SetByte sets field to a single DTByte value.
+func (b *IFDBuilder) SetByte(field uint16, v byte) {
+	b.set(field, DTByte, 1, []byte{v})
+}
+
+// SetShort sets field to a single DTShort value.
+func (b *IFDBuilder) SetShort(field uint16, v uint16) {
+	val := make([]byte, 2)
+	b.order.PutUint16(val, v)
+	b.set(field, DTShort, 1, val)
+}
+
+// SetLong sets field to a single DTLong value.
+func (b *IFDBuilder) SetLong(field uint16, v uint32) {
+	val := make([]byte, 4)
+	b.order.PutUint32(val, v)
+	b.set(field, DTLong, 1, val)
+}
+
+// SetIFDPointer sets field to v, a pointer to another IFD within the same
+// tiff structure (e.g. exif's ExifIFD/GPSInfo tags). It uses DTLong for
+// classic TIFF and DTIFD8 for BigTIFF, matching the wider inline value slot
+// -- a plain SetLong's 4-byte value would be mispositioned within an 8-byte
+// BigTIFF slot. v is typically 0 as a placeholder, to be overwritten with
+// PatchOffset once the pointed-to IFD's real offset is known.
+func (b *IFDBuilder) SetIFDPointer(field uint16, v uint64) {
+	if b.isBig {
+		val := make([]byte, 8)
+		b.order.PutUint64(val, v)
+		b.set(field, DTIFD8, 1, val)
+		return
+	}
+	val := make([]byte, 4)
+	b.order.PutUint32(val, uint32(v))
+	b.set(field, DTLong, 1, val)
+}
+
+// SetASCII sets field to a NUL-terminated DTAscii string.
+func (b *IFDBuilder) SetASCII(field uint16, v string) {
+	val := append([]byte(v), 0x00)
+	b.set(field, DTAscii, uint32(len(val)), val)
+}
+
+// SetRational sets field to a single DTRational value expressed as num/denom.
+func (b *IFDBuilder) SetRational(field uint16, num, denom uint32) {
+	val := make([]byte, 8)
+	b.order.PutUint32(val[:4], num)
+	b.order.PutUint32(val[4:], denom)
+	b.set(field, DTRational, 1, val)
+}
+
+// SetRationals sets field to a sequence of DTRational values, such as the
+// three GPS DMS components.
+func (b *IFDBuilder) SetRationals(field uint16, pairs [][2]uint32) {
+	val := make([]byte, 8*len(pairs))
+	for i, p := range pairs {
+		b.order.PutUint32(val[i*8:], p[0])
+		b.order.PutUint32(val[i*8+4:], p[1])
+	}
+	b.set(field, DTRational, uint32(len(pairs)), val)
+}
+
+// SetUndefined sets field to an opaque DTUndefined blob.
+func (b *IFDBuilder) SetUndefined(field uint16, v []byte) {
+	b.set(field, DTUndefined, uint32(len(v)), v)
+}
+
+// Remove deletes field from the IFD, if present.
+func (b *IFDBuilder) Remove(field uint16) {
+	delete(b.tags, field)
+	delete(b.rawTags, field)
+}
+
+// Tag returns the tag currently set for field, if any.
+func (b *IFDBuilder) Tag(field uint16) (*Tag, bool) {
+	t, ok := b.tags[field]
+	return t, ok
+}
+
+// RawTag returns the opaque, unrecognized-type tag currently set for field,
+// if any. See RawTag (the type) for why these exist separately from Tag.
+func (b *IFDBuilder) RawTag(field uint16) (*RawTag, bool) {
+	t, ok := b.rawTags[field]
+	return t, ok
+}
+
+// Tags returns every recognized-type tag currently set on b, in no
+// particular order. Callers that need to enumerate an IFD being built --
+// e.g. to commit it back into a higher-level structure -- should use this
+// rather than guessing at field numbers.
+func (b *IFDBuilder) Tags() []*Tag {
+	out := make([]*Tag, 0, len(b.tags))
+	for _, t := range b.tags {
+		out = append(out, t)
+	}
+	return out
+}
+
+// RawTags returns every opaque, unrecognized-type tag currently set on b, in
+// no particular order.
+func (b *IFDBuilder) RawTags() []*RawTag {
+	out := make([]*RawTag, 0, len(b.rawTags))
+	for _, t := range b.rawTags {
+		out = append(out, t)
+	}
+	return out
+}
+
+// valueWidth is the size, in bytes, of the inline value slot in an encoded
+// tag entry: 4 for classic TIFF, 8 for BigTIFF.
+func (b *IFDBuilder) valueWidth() int64 {
+	if b.isBig {
+		return 8
+	}
+	return 4
+}
+
+// countWidth is the size, in bytes, of the count field in an encoded tag
+// entry: 4 for classic TIFF, 8 for BigTIFF.
+func (b *IFDBuilder) countWidth() int64 {
+	if b.isBig {
+		return 8
+	}
+	return 4
+}
+
+// encode appends the IFD to buf, which already holds everything written so
+// far starting from the beginning of the tiff structure (so buf.Len() is
+// always an absolute offset). It writes 0 as the next-IFD pointer and
+// returns the absolute offset of that pointer's bytes within buf, so the
+// caller can patch it in once the following IFD's offset is known. valuePos
+// reports, for each tag whose value fits inline, the absolute offset of its
+// value bytes within buf -- used by exif.Exif.Encode to patch sub-IFD
+// pointer tags (e.g. ExifIFD, GPSInfo) once the sub-IFD's actual offset is
+// known.
+func (b *IFDBuilder) encode(buf *bytes.Buffer) (nextPtrPos int64, valuePos map[uint16]int64, err error) {
+	valuePos = make(map[uint16]int64)
+	fields := make([]uint16, 0, len(b.tags)+len(b.rawTags))
+	for f := range b.tags {
+		fields = append(fields, f)
+	}
+	for f := range b.rawTags {
+		fields = append(fields, f)
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i] < fields[j] })
+
+	if b.isBig {
+		if err := binary.Write(buf, b.order, int64(len(fields))); err != nil {
+			return 0, nil, err
+		}
+	} else {
+		if err := binary.Write(buf, b.order, int16(len(fields))); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	// A tag entry is fieldNum (2 bytes) + type (2 bytes) + count
+	// (countWidth) + inline value/offset (valueWidth).
+	entrySize := int64(4) + b.countWidth() + b.valueWidth()
+	poolOffset := int64(buf.Len()) + int64(len(fields))*entrySize + b.valueWidth()
+
+	var pool bytes.Buffer
+	for _, f := range fields {
+		fieldNum, typ, count, val, err := b.entryFor(f)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if err := binary.Write(buf, b.order, fieldNum); err != nil {
+			return 0, nil, err
+		}
+		if err := binary.Write(buf, b.order, typ); err != nil {
+			return 0, nil, err
+		}
+		if b.isBig {
+			if err := binary.Write(buf, b.order, int64(count)); err != nil {
+				return 0, nil, err
+			}
+		} else {
+			if err := binary.Write(buf, b.order, int32(count)); err != nil {
+				return 0, nil, err
+			}
+		}
+
+		if int64(len(val)) <= b.valueWidth() {
+			valuePos[f] = int64(buf.Len())
+			padded := make([]byte, b.valueWidth())
+			copy(padded, val)
+			buf.Write(padded)
+		} else {
+			valOffset := poolOffset + int64(pool.Len())
+			pool.Write(val)
+			offsetVal := make([]byte, b.valueWidth())
+			if b.isBig {
+				b.order.PutUint64(offsetVal, uint64(valOffset))
+			} else {
+				b.order.PutUint32(offsetVal, uint32(valOffset))
+			}
+			buf.Write(offsetVal)
+		}
+	}
+
+	nextPtrPos = int64(buf.Len())
+	if err := writeOffset(buf, b.order, b.isBig, 0); err != nil {
+		return 0, nil, err
+	}
+
+	buf.Write(pool.Bytes())
+
+	return nextPtrPos, valuePos, nil
+}
+
+// entryFor returns the field number, type, count and value bytes to encode
+// for field, whether it's a recognized Tag or an opaque RawTag. RawTags
+// bypass typeSize entirely -- their Raw bytes are written as-is -- since the
+// whole point of a RawTag is that DefaultRegistry has no TypeInfo for it.
+func (b *IFDBuilder) entryFor(field uint16) (fieldNum, typ uint16, count uint32, val []byte, err error) {
+	if raw, ok := b.rawTags[field]; ok {
+		return raw.FieldNum, raw.Type, raw.Count, raw.Raw, nil
+	}
+	t := b.tags[field]
+	if _, ok := typeSize(t.Type); !ok {
+		return 0, 0, 0, nil, newTiffError("cannot encode tag with unknown type", nil)
+	}
+	return t.FieldNum, t.Type, t.Count, t.Val, nil
+}
+
+func writeOffset(w io.Writer, order binary.ByteOrder, isBig bool, offset int64) error {
+	if isBig {
+		return binary.Write(w, order, offset)
+	}
+	return binary.Write(w, order, int32(offset))
+}
+
+// PatchOffset overwrites the 4-byte (or 8-byte, for BigTIFF) offset value at
+// pos within out. It's used to back-patch pointers -- next-IFD offsets,
+// sub-IFD pointer tags -- once the position they refer to is known, after
+// the bytes around it have already been written.
+func PatchOffset(out []byte, order binary.ByteOrder, isBig bool, pos, offset int64) {
+	tmp := bytes.NewBuffer(nil)
+	writeOffset(tmp, order, isBig, offset)
+	copy(out[pos:], tmp.Bytes())
+}
+
+// WriteHeader writes the tiff byte-order mark and magic number (42, or 43
+// for BigTIFF) to buf, followed by a placeholder first-IFD offset. It
+// returns the position of that placeholder so the caller can PatchOffset it
+// in once the first IFD's offset is known.
+func WriteHeader(buf *bytes.Buffer, order binary.ByteOrder, isBig bool) (firstIFDOffsetPos int64, err error) {
+	if order == binary.LittleEndian {
+		buf.WriteString("II")
+	} else {
+		buf.WriteString("MM")
+	}
+
+	var magic int16 = 42
+	if isBig {
+		magic = 43
+	}
+	if err := binary.Write(buf, order, magic); err != nil {
+		return 0, err
+	}
+
+	if isBig {
+		// BigTIFF header: byte-size-of-offsets, then a reserved uint16.
+		if err := binary.Write(buf, order, int16(8)); err != nil {
+			return 0, err
+		}
+		if err := binary.Write(buf, order, int16(0)); err != nil {
+			return 0, err
+		}
+	}
+
+	firstIFDOffsetPos = int64(buf.Len())
+	if err := writeOffset(buf, order, isBig, 0); err != nil {
+		return 0, err
+	}
+	return firstIFDOffsetPos, nil
+}
+
+// EncodeIFD appends b to buf as described by the unexported encode method,
+// exported so other packages (e.g. exif, when placing Exif/GPS sub-IFDs
+// pointed to from another IFD) can lay out IFDs explicitly.
+func (b *IFDBuilder) EncodeIFD(buf *bytes.Buffer) (nextPtrPos int64, valuePos map[uint16]int64, err error) {
+	return b.encode(buf)
+}
+
+// Encode serializes tf as a tiff byte stream: the II/MM header, the magic
+// number (42, or 43 for BigTIFF), each of tf.Dirs in order, and their
+// out-of-line value data.
+func (tf *Tiff) Encode(w io.Writer) error {
+	var buf bytes.Buffer
+
+	firstIFDOffsetPos, err := WriteHeader(&buf, tf.Order, tf.IsBig)
+	if err != nil {
+		return err
+	}
+
+	offsets := make([]int64, len(tf.Dirs))
+	nextPtrPositions := make([]int64, len(tf.Dirs))
+	for i, d := range tf.Dirs {
+		b := NewIFDBuilderFromDir(d, tf.Order, tf.IsBig)
+		offsets[i] = int64(buf.Len())
+		pos, _, err := b.encode(&buf)
+		if err != nil {
+			return err
+		}
+		nextPtrPositions[i] = pos
+	}
+
+	out := buf.Bytes()
+	if len(offsets) > 0 {
+		PatchOffset(out, tf.Order, tf.IsBig, firstIFDOffsetPos, offsets[0])
+	}
+	for i := 0; i < len(offsets)-1; i++ {
+		PatchOffset(out, tf.Order, tf.IsBig, nextPtrPositions[i], offsets[i+1])
+	}
+
+	_, err = w.Write(out)
+	return err
+}
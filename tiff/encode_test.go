@@ -0,0 +1,104 @@
+package tiff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// TestEncodeRoundTripsRawTag checks that a tag of a type DefaultRegistry
+// doesn't recognize -- preserved as a *RawTag on decode -- survives being
+// carried through an IFDBuilder and re-encoded, rather than being silently
+// dropped.
+func TestEncodeRoundTripsRawTag(t *testing.T) {
+	order := binary.LittleEndian
+
+	d := &Dir{
+		Tags: []*Tag{{FieldNum: 0x0112, Type: DTShort, Count: 1, Val: []byte{0x01, 0x00}, Order: order}},
+		RawTags: []*RawTag{
+			{FieldNum: 0x9999, Type: 0xffff, Count: 4, Raw: []byte{0xde, 0xad, 0xbe, 0xef}},
+		},
+	}
+
+	b := NewIFDBuilderFromDir(d, order, false)
+
+	var buf bytes.Buffer
+	pos, err := WriteHeader(&buf, order, false)
+	if err != nil {
+		t.Fatalf("WriteHeader: %v", err)
+	}
+	ifdOffset := int64(buf.Len())
+	if _, _, err := b.EncodeIFD(&buf); err != nil {
+		t.Fatalf("EncodeIFD: %v", err)
+	}
+	out := buf.Bytes()
+	PatchOffset(out, order, false, pos, ifdOffset)
+
+	got, err := Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if len(got.Dirs) != 1 {
+		t.Fatalf("got %d dirs, want 1", len(got.Dirs))
+	}
+
+	dir := got.Dirs[0]
+	if len(dir.RawTags) != 1 {
+		t.Fatalf("got %d RawTags after round trip, want 1 (raw tag was dropped)", len(dir.RawTags))
+	}
+	raw := dir.RawTags[0]
+	if raw.FieldNum != 0x9999 || raw.Type != 0xffff || raw.Count != 4 {
+		t.Errorf("unexpected RawTag: %+v", raw)
+	}
+	if !bytes.Equal(raw.Raw, []byte{0xde, 0xad, 0xbe, 0xef}) {
+		t.Errorf("expected raw value to be preserved, got % x", raw.Raw)
+	}
+}
+
+// TestEncodeBigTIFFOutOfLineValue checks that a BigTIFF IFD with an
+// out-of-line value (one that doesn't fit in the 8-byte inline slot) places
+// that value at the offset it actually wrote it to. A wrong entrySize for
+// BigTIFF's wider (8-byte) count field would make poolOffset -- and so every
+// out-of-line value's recorded offset -- too low.
+func TestEncodeBigTIFFOutOfLineValue(t *testing.T) {
+	order := binary.LittleEndian
+	b := NewIFDBuilder(order, true)
+	// Two rationals (16 bytes) don't fit in BigTIFF's 8-byte inline slot.
+	b.SetRationals(0x0001, [][2]uint32{{1, 2}, {3, 4}})
+
+	var buf bytes.Buffer
+	pos, err := WriteHeader(&buf, order, true)
+	if err != nil {
+		t.Fatalf("WriteHeader: %v", err)
+	}
+	ifdOffset := int64(buf.Len())
+	if _, _, err := b.EncodeIFD(&buf); err != nil {
+		t.Fatalf("EncodeIFD: %v", err)
+	}
+	out := buf.Bytes()
+	PatchOffset(out, order, true, pos, ifdOffset)
+
+	got, err := Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if len(got.Dirs) != 1 || len(got.Dirs[0].Tags) != 1 {
+		t.Fatalf("got dirs=%+v, want one dir with one tag", got.Dirs)
+	}
+
+	tag := got.Dirs[0].Tags[0]
+	num, denom, err := tag.Rat2(0)
+	if err != nil {
+		t.Fatalf("Rat2(0): %v", err)
+	}
+	if num != 1 || denom != 2 {
+		t.Errorf("Rat2(0) = %d/%d, want 1/2 (value read from wrong offset)", num, denom)
+	}
+	num, denom, err = tag.Rat2(1)
+	if err != nil {
+		t.Fatalf("Rat2(1): %v", err)
+	}
+	if num != 3 || denom != 4 {
+		t.Errorf("Rat2(1) = %d/%d, want 3/4 (value read from wrong offset)", num, denom)
+	}
+}
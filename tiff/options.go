@@ -0,0 +1,41 @@
+package tiff
+
+// DecoderOptions bounds the resources Decode will use, to harden against
+// malformed or malicious tiff data that declares huge tag counts or
+// out-of-range offsets. Any field left at zero disables that particular
+// limit, except MaxRecursionInSubIFDs (see below).
+type DecoderOptions struct {
+	// MaxTags caps the number of tags DecodeDir will read from a single
+	// IFD's tag count field.
+	MaxTags int
+	// MaxValueBytes caps a single tag's declared count*elementSize.
+	MaxValueBytes int64
+	// MaxIFDs caps how many IFDs Decode will follow along the tiff's
+	// "next IFD" chain.
+	MaxIFDs int
+	// MaxIFDChainDepth caps how many links of the next-IFD chain Decode
+	// will follow, independently of MaxIFDs -- kept distinct so callers
+	// tuning total-IFD budgets and chain-depth budgets don't have to
+	// conflate the two.
+	MaxIFDChainDepth int
+	// MaxRecursionInSubIFDs caps how many levels of sub-IFD the exif
+	// package will descend into. The exif package reads only the Exif and
+	// GPS sub-IFDs, one level below IFD0, so any positive value reads them
+	// and zero skips them.
+	MaxRecursionInSubIFDs int
+	// AllowTruncated, if true, tolerates a tag value that runs past the end
+	// of the reader by reading only the bytes that are available, instead
+	// of failing the whole decode.
+	AllowTruncated bool
+}
+
+// DefaultDecoderOptions is applied by Decode, DecodeDir and DecodeTag.
+// It's generous enough for any well-formed file encountered in practice,
+// while still bounding worst-case memory use against hostile input.
+var DefaultDecoderOptions = DecoderOptions{
+	MaxTags:               1 << 16,
+	MaxValueBytes:         1 << 28, // 256 MiB
+	MaxIFDs:               1 << 10,
+	MaxIFDChainDepth:      1 << 10,
+	MaxRecursionInSubIFDs: 8,
+}
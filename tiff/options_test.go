@@ -0,0 +1,67 @@
+package tiff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// buildMinimalTiff returns a well-formed classic tiff with a single IFD
+// holding nTags short-typed tags, each with an inline value.
+func buildMinimalTiff(order binary.ByteOrder, nTags int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("II")
+	binary.Write(&buf, order, int16(42))
+	binary.Write(&buf, order, int32(8)) // first IFD at offset 8
+
+	binary.Write(&buf, order, int16(nTags))
+	for i := 0; i < nTags; i++ {
+		binary.Write(&buf, order, uint16(i+1)) // field num
+		binary.Write(&buf, order, uint16(DTShort))
+		binary.Write(&buf, order, int32(1))
+		binary.Write(&buf, order, uint16(7))
+		binary.Write(&buf, order, uint16(0)) // padding to fill the 4-byte slot
+	}
+	binary.Write(&buf, order, int32(0)) // next IFD offset
+
+	return buf.Bytes()
+}
+
+func TestDecodeWithOptionsRejectsTooManyTags(t *testing.T) {
+	data := buildMinimalTiff(binary.LittleEndian, 4)
+	opts := DefaultDecoderOptions
+	opts.MaxTags = 2
+
+	_, err := DecodeWithOptions(bytes.NewReader(data), opts)
+	if err == nil {
+		t.Fatal("expected an error for an IFD exceeding MaxTags, got nil")
+	}
+}
+
+func TestDecodeWithOptionsAllowsWithinLimits(t *testing.T) {
+	data := buildMinimalTiff(binary.LittleEndian, 4)
+
+	tif, err := DecodeWithOptions(bytes.NewReader(data), DefaultDecoderOptions)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if len(tif.Dirs) != 1 || len(tif.Dirs[0].Tags) != 4 {
+		t.Fatalf("unexpected decode result: %+v", tif)
+	}
+}
+
+func TestDecodeDetectsCyclicIFDChain(t *testing.T) {
+	order := binary.LittleEndian
+	var buf bytes.Buffer
+	buf.WriteString("II")
+	binary.Write(&buf, order, int16(42))
+	binary.Write(&buf, order, int32(8)) // first IFD at offset 8
+
+	binary.Write(&buf, order, int16(0)) // no tags
+	binary.Write(&buf, order, int32(8)) // next IFD points back at itself
+
+	_, err := Decode(bytes.NewReader(buf.Bytes()))
+	if err == nil {
+		t.Fatal("expected an error for a cyclic IFD chain, got nil")
+	}
+}
@@ -0,0 +1,186 @@
+package tiff
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// BigTIFF field types, in addition to the TIFF 6.0 set in tag.go.
+const (
+	DTLong8  = 16
+	DTSLong8 = 17
+	DTIFD8   = 18
+)
+
+// TypeInfo describes a TIFF field type: the size of one element, a decode
+// function from raw bytes to a Go value, and a display name.
+type TypeInfo struct {
+	// Size is the size in bytes of a single element of this type.
+	Size uint32
+	// Decode parses raw (a whole multiple of Size) into a Go value.
+	Decode func(raw []byte, order binary.ByteOrder) (interface{}, error)
+	// Name is the type's display name, e.g. "SHORT" or "LONG8".
+	Name string
+}
+
+func (t TypeInfo) String() string {
+	if t.Name == "" {
+		return "UNKNOWN"
+	}
+	return t.Name
+}
+
+// TypeRegistry maps numeric TIFF field type codes to the TypeInfo needed to
+// decode them. Callers can register vendor-specific types (e.g. Canon or
+// Sony maker-note subtypes) against their own registry, or against
+// DefaultRegistry to make them available everywhere, without forking this
+// package.
+type TypeRegistry struct {
+	types map[uint16]TypeInfo
+}
+
+// NewTypeRegistry returns an empty TypeRegistry.
+func NewTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{types: make(map[uint16]TypeInfo)}
+}
+
+// Register adds t to the registry under code, overwriting any existing
+// entry.
+func (r *TypeRegistry) Register(code uint16, t TypeInfo) {
+	r.types[code] = t
+}
+
+// Lookup returns the TypeInfo registered for code, if any.
+func (r *TypeRegistry) Lookup(code uint16) (TypeInfo, bool) {
+	t, ok := r.types[code]
+	return t, ok
+}
+
+// DefaultRegistry is pre-populated with the TIFF 6.0 field types and the
+// BigTIFF additions (LONG8, SLONG8, IFD8). DecodeTag consults it to decide
+// how many bytes a tag's value occupies.
+var DefaultRegistry = NewTypeRegistry()
+
+// RegisterType adds t to DefaultRegistry under code, so DecodeTag (and
+// anything else consulting DefaultRegistry) recognizes it.
+func RegisterType(code uint16, t TypeInfo) {
+	DefaultRegistry.Register(code, t)
+}
+
+func decodeBytes(raw []byte, order binary.ByteOrder) (interface{}, error) {
+	return raw, nil
+}
+
+func decodeSBytes(raw []byte, order binary.ByteOrder) (interface{}, error) {
+	out := make([]int8, len(raw))
+	for i, b := range raw {
+		out[i] = int8(b)
+	}
+	return out, nil
+}
+
+func decodeASCII(raw []byte, order binary.ByteOrder) (interface{}, error) {
+	s := string(raw)
+	if len(s) > 0 && s[len(s)-1] == 0x00 {
+		s = s[:len(s)-1]
+	}
+	return s, nil
+}
+
+func decodeShorts(raw []byte, order binary.ByteOrder) (interface{}, error) {
+	out := make([]uint16, len(raw)/2)
+	for i := range out {
+		out[i] = order.Uint16(raw[i*2:])
+	}
+	return out, nil
+}
+
+func decodeSShorts(raw []byte, order binary.ByteOrder) (interface{}, error) {
+	out := make([]int16, len(raw)/2)
+	for i := range out {
+		out[i] = int16(order.Uint16(raw[i*2:]))
+	}
+	return out, nil
+}
+
+func decodeLongs(raw []byte, order binary.ByteOrder) (interface{}, error) {
+	out := make([]uint32, len(raw)/4)
+	for i := range out {
+		out[i] = order.Uint32(raw[i*4:])
+	}
+	return out, nil
+}
+
+func decodeSLongs(raw []byte, order binary.ByteOrder) (interface{}, error) {
+	out := make([]int32, len(raw)/4)
+	for i := range out {
+		out[i] = int32(order.Uint32(raw[i*4:]))
+	}
+	return out, nil
+}
+
+func decodeRationals(raw []byte, order binary.ByteOrder) (interface{}, error) {
+	out := make([][2]uint32, len(raw)/8)
+	for i := range out {
+		out[i] = [2]uint32{order.Uint32(raw[i*8:]), order.Uint32(raw[i*8+4:])}
+	}
+	return out, nil
+}
+
+func decodeSRationals(raw []byte, order binary.ByteOrder) (interface{}, error) {
+	out := make([][2]int32, len(raw)/8)
+	for i := range out {
+		out[i] = [2]int32{int32(order.Uint32(raw[i*8:])), int32(order.Uint32(raw[i*8+4:]))}
+	}
+	return out, nil
+}
+
+func decodeFloats(raw []byte, order binary.ByteOrder) (interface{}, error) {
+	out := make([]float32, len(raw)/4)
+	for i := range out {
+		out[i] = math.Float32frombits(order.Uint32(raw[i*4:]))
+	}
+	return out, nil
+}
+
+func decodeDoubles(raw []byte, order binary.ByteOrder) (interface{}, error) {
+	out := make([]float64, len(raw)/8)
+	for i := range out {
+		out[i] = math.Float64frombits(order.Uint64(raw[i*8:]))
+	}
+	return out, nil
+}
+
+func decodeLong8s(raw []byte, order binary.ByteOrder) (interface{}, error) {
+	out := make([]uint64, len(raw)/8)
+	for i := range out {
+		out[i] = order.Uint64(raw[i*8:])
+	}
+	return out, nil
+}
+
+func decodeSLong8s(raw []byte, order binary.ByteOrder) (interface{}, error) {
+	out := make([]int64, len(raw)/8)
+	for i := range out {
+		out[i] = int64(order.Uint64(raw[i*8:]))
+	}
+	return out, nil
+}
+
+func init() {
+	RegisterType(DTByte, TypeInfo{Size: 1, Decode: decodeBytes, Name: "BYTE"})
+	RegisterType(DTAscii, TypeInfo{Size: 1, Decode: decodeASCII, Name: "ASCII"})
+	RegisterType(DTShort, TypeInfo{Size: 2, Decode: decodeShorts, Name: "SHORT"})
+	RegisterType(DTLong, TypeInfo{Size: 4, Decode: decodeLongs, Name: "LONG"})
+	RegisterType(DTRational, TypeInfo{Size: 8, Decode: decodeRationals, Name: "RATIONAL"})
+	RegisterType(DTSByte, TypeInfo{Size: 1, Decode: decodeSBytes, Name: "SBYTE"})
+	RegisterType(DTUndefined, TypeInfo{Size: 1, Decode: decodeBytes, Name: "UNDEFINED"})
+	RegisterType(DTSShort, TypeInfo{Size: 2, Decode: decodeSShorts, Name: "SSHORT"})
+	RegisterType(DTSLong, TypeInfo{Size: 4, Decode: decodeSLongs, Name: "SLONG"})
+	RegisterType(DTSRational, TypeInfo{Size: 8, Decode: decodeSRationals, Name: "SRATIONAL"})
+	RegisterType(DTFloat, TypeInfo{Size: 4, Decode: decodeFloats, Name: "FLOAT"})
+	RegisterType(DTDouble, TypeInfo{Size: 8, Decode: decodeDoubles, Name: "DOUBLE"})
+	RegisterType(DTLong8, TypeInfo{Size: 8, Decode: decodeLong8s, Name: "LONG8"})
+	RegisterType(DTSLong8, TypeInfo{Size: 8, Decode: decodeSLong8s, Name: "SLONG8"})
+	RegisterType(DTIFD8, TypeInfo{Size: 8, Decode: decodeLong8s, Name: "IFD8"})
+}
@@ -0,0 +1,52 @@
+package tiff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestDecodeTagUnrecognizedTypePreservesRaw(t *testing.T) {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, uint16(0x1234)) // field num
+	binary.Write(&buf, binary.LittleEndian, uint16(0xffff)) // unregistered type
+	binary.Write(&buf, binary.LittleEndian, int32(4))       // count
+	buf.Write([]byte{0xde, 0xad, 0xbe, 0xef})               // inline value
+
+	tag, raw, err := DecodeTag(bytes.NewReader(buf.Bytes()), binary.LittleEndian, false)
+	if err != nil {
+		t.Fatalf("DecodeTag: %v", err)
+	}
+	if tag != nil {
+		t.Fatalf("expected no *Tag for an unrecognized type, got %v", tag)
+	}
+	if raw == nil {
+		t.Fatal("expected a *RawTag for an unrecognized type")
+	}
+	if raw.FieldNum != 0x1234 || raw.Type != 0xffff || raw.Count != 4 {
+		t.Errorf("unexpected RawTag: %+v", raw)
+	}
+	if !bytes.Equal(raw.Raw, []byte{0xde, 0xad, 0xbe, 0xef}) {
+		t.Errorf("expected raw value to be preserved, got % x", raw.Raw)
+	}
+}
+
+func TestRegisterTypeAddsDecodableType(t *testing.T) {
+	const vendorType = 0x7f01
+	RegisterType(vendorType, TypeInfo{
+		Size: 2,
+		Decode: func(raw []byte, order binary.ByteOrder) (interface{}, error) {
+			return order.Uint16(raw), nil
+		},
+		Name: "VENDORSHORT",
+	})
+
+	tag := &Tag{Type: vendorType, Count: 1, Val: []byte{0x01, 0x00}, Order: binary.LittleEndian}
+	v, err := tag.DecodedValue()
+	if err != nil {
+		t.Fatalf("DecodedValue: %v", err)
+	}
+	if v.(uint16) != 1 {
+		t.Errorf("got %v, want 1", v)
+	}
+}
@@ -0,0 +1,257 @@
+package tiff
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// TIFF 6.0 field types, as defined in the spec section 2, "Image File
+// Directory".
+const (
+	DTByte      = 1
+	DTAscii     = 2
+	DTShort     = 3
+	DTLong      = 4
+	DTRational  = 5
+	DTSByte     = 6
+	DTUndefined = 7
+	DTSShort    = 8
+	DTSLong     = 9
+	DTSRational = 10
+	DTFloat     = 11
+	DTDouble    = 12
+)
+
+// typeSize looks up the size in bytes of a single value of the given field
+// type via DefaultRegistry.
+func typeSize(code uint16) (uint32, bool) {
+	info, ok := DefaultRegistry.Lookup(code)
+	if !ok {
+		return 0, false
+	}
+	return info.Size, true
+}
+
+// RawTag preserves a tag whose field type isn't present in DefaultRegistry,
+// so its value bytes can be carried through decode/encode round trips (by
+// scrubbers, for instance) instead of being silently dropped. Its value is
+// treated as opaque: the element size for an unregistered type is unknown,
+// so Raw holds exactly Count bytes.
+type RawTag struct {
+	FieldNum uint16
+	Type     uint16
+	Count    uint32
+	Raw      []byte
+	Offset   int64
+}
+
+func (t *RawTag) String() string {
+	return fmt.Sprintf("RawTag{Field: 0x%04x, Type: %d, Count: %d}", t.FieldNum, t.Type, t.Count)
+}
+
+// Tag reflects the parsed content of a tiff IFD tag.
+type Tag struct {
+	// FieldNum is the identifier for the tag's field, e.g. 0x0112 is the
+	// "Orientation" field.
+	FieldNum uint16
+	// Type is the tag's value type, one of the DT* constants above.
+	Type uint16
+	// Count is the number of values.
+	Count uint32
+	// Val holds the decoded, raw bytes of the tag's value, always in the
+	// tiff's native byte order (t.Order).
+	Val []byte
+	// ValOffset, if non-zero, is the offset (relative to the start of the
+	// tiff structure) at which Val was found, for values that didn't fit
+	// inline in the tag entry.
+	ValOffset uint32
+
+	Order binary.ByteOrder
+}
+
+// DecodeTag parses a tiff-encoded tag from r. The first read from r should
+// be at the first byte of the tag entry. ReadAt offsets should generally be
+// relative to the beginning of the tiff structure (not relative to the
+// beginning of the tag).
+//
+// If the tag's field type is registered in DefaultRegistry, DecodeTag
+// returns a *Tag with exactly one of t/raw non-nil. Otherwise it returns a
+// *RawTag holding the value's raw bytes unchanged, so callers that only
+// need to preserve (rather than interpret) unrecognized tags -- the
+// encoder, scrubbers -- don't lose data on round trips.
+//
+// DecodeTag applies DefaultDecoderOptions; use DecodeTagWithOptions to
+// decode against untrusted input with tighter bounds.
+func DecodeTag(r ReadAtReader, order binary.ByteOrder, isBigTIFF bool) (t *Tag, raw *RawTag, err error) {
+	return DecodeTagWithOptions(r, order, isBigTIFF, DefaultDecoderOptions, -1)
+}
+
+// DecodeTagWithOptions is DecodeTag with explicit DecoderOptions. size is
+// the total length, in bytes, of the tiff structure r reads from, used to
+// reject a value offset/length that would run past the end of the data;
+// pass -1 if the size isn't known, which disables that particular check.
+func DecodeTagWithOptions(r ReadAtReader, order binary.ByteOrder, isBigTIFF bool, opts DecoderOptions, size int64) (t *Tag, raw *RawTag, err error) {
+	var fieldNum, typ uint16
+	if err := binary.Read(r, order, &fieldNum); err != nil {
+		return nil, nil, newTiffError("failed to read tag field number", err)
+	}
+	if err := binary.Read(r, order, &typ); err != nil {
+		return nil, nil, newTiffError("failed to read tag type", err)
+	}
+
+	elemSize, recognized := typeSize(typ)
+	if !recognized {
+		// The element size for an unregistered type is unknown; treat the
+		// value as a flat byte blob so we can at least preserve it.
+		elemSize = 1
+	}
+
+	var count uint32
+	if isBigTIFF {
+		var c int64
+		if err := binary.Read(r, order, &c); err != nil {
+			return nil, nil, newTiffError("failed to read tag count", err)
+		}
+		count = uint32(c)
+	} else {
+		var c int32
+		if err := binary.Read(r, order, &c); err != nil {
+			return nil, nil, newTiffError("failed to read tag count", err)
+		}
+		count = uint32(c)
+	}
+
+	valLen := int64(count) * int64(elemSize)
+	if opts.MaxValueBytes > 0 && valLen > opts.MaxValueBytes {
+		return nil, nil, newTiffError("tag value exceeds MaxValueBytes", nil)
+	}
+
+	valWidth := int64(4)
+	if isBigTIFF {
+		valWidth = 8
+	}
+
+	var val []byte
+	var valOffset int64
+	if valLen <= valWidth {
+		val = make([]byte, valWidth)
+		if _, err := io.ReadFull(r, val); err != nil {
+			return nil, nil, newTiffError("failed to read inline tag value", err)
+		}
+		val = val[:valLen]
+	} else {
+		offset, err := readOffset(r, order, isBigTIFF)
+		if err != nil {
+			return nil, nil, newTiffError("failed to read tag value offset", err)
+		}
+		valOffset = offset
+
+		if size >= 0 && (offset < 0 || offset > size || valLen > size-offset) {
+			if !opts.AllowTruncated {
+				return nil, nil, newTiffError("tag value runs past end of data", nil)
+			}
+			avail := size - offset
+			if offset < 0 || avail < 0 {
+				avail = 0
+			}
+			valLen = avail
+		}
+
+		val = make([]byte, valLen)
+		if valLen > 0 {
+			if _, err := r.ReadAt(val, offset); err != nil {
+				return nil, nil, newTiffError("failed to read tag value", err)
+			}
+		}
+	}
+
+	if !recognized {
+		return nil, &RawTag{FieldNum: fieldNum, Type: typ, Count: count, Raw: val, Offset: valOffset}, nil
+	}
+
+	return &Tag{
+		FieldNum:  fieldNum,
+		Type:      typ,
+		Count:     count,
+		Val:       val,
+		ValOffset: uint32(valOffset),
+		Order:     order,
+	}, nil, nil
+}
+
+// DecodedValue returns t's value decoded via DefaultRegistry's TypeInfo for
+// t.Type, e.g. []uint16 for DTShort or string for DTAscii. Most callers
+// interested in one specific field should prefer Int/Rat2/StringVal; this
+// is for generic tooling (and vendor-registered types) that needs to handle
+// any tag without a type switch.
+func (t *Tag) DecodedValue() (interface{}, error) {
+	info, ok := DefaultRegistry.Lookup(t.Type)
+	if !ok {
+		return nil, errors.New("tiff: no decoder registered for this tag's type")
+	}
+	return info.Decode(t.Val, t.Order)
+}
+
+func (t *Tag) String() string {
+	return fmt.Sprintf("Tag{Field: 0x%04x, Type: %d, Count: %d}", t.FieldNum, t.Type, t.Count)
+}
+
+// Int returns the ith value of the tag as an integer, for integral field
+// types. i is zero-based.
+func (t *Tag) Int(i int) (int64, error) {
+	size, ok := typeSize(t.Type)
+	if !ok || int64(i+1)*int64(size) > int64(len(t.Val)) {
+		return 0, errors.New("tiff: index out of range")
+	}
+	switch t.Type {
+	case DTByte, DTUndefined:
+		return int64(t.Val[i]), nil
+	case DTSByte:
+		return int64(int8(t.Val[i])), nil
+	case DTShort:
+		return int64(t.Order.Uint16(t.Val[i*2:])), nil
+	case DTSShort:
+		return int64(int16(t.Order.Uint16(t.Val[i*2:]))), nil
+	case DTLong:
+		return int64(t.Order.Uint32(t.Val[i*4:])), nil
+	case DTSLong:
+		return int64(int32(t.Order.Uint32(t.Val[i*4:]))), nil
+	case DTLong8, DTSLong8, DTIFD8:
+		// The uint64->int64 conversion reinterprets the same bit pattern,
+		// which is exactly the two's complement value for DTSLong8.
+		return int64(t.Order.Uint64(t.Val[i*8:])), nil
+	}
+	return 0, errors.New("tiff: incompatible type")
+}
+
+// Rat2 returns the ith value of the tag as a numerator/denominator pair, for
+// the rational field types. i is zero-based.
+func (t *Tag) Rat2(i int) (num, denom int64, err error) {
+	if t.Type != DTRational && t.Type != DTSRational {
+		return 0, 0, errors.New("tiff: incompatible type")
+	}
+	if (i+1)*8 > len(t.Val) {
+		return 0, 0, errors.New("tiff: index out of range")
+	}
+	n := t.Order.Uint32(t.Val[i*8:])
+	d := t.Order.Uint32(t.Val[i*8+4:])
+	if t.Type == DTSRational {
+		return int64(int32(n)), int64(int32(d)), nil
+	}
+	return int64(n), int64(d), nil
+}
+
+// StringVal returns the tag's value as a string, for DTAscii tags. The
+// trailing NUL required by the spec is stripped if present.
+func (t *Tag) StringVal() (string, error) {
+	if t.Type != DTAscii {
+		return "", errors.New("tiff: incompatible type")
+	}
+	s := string(t.Val)
+	if len(s) > 0 && s[len(s)-1] == 0x00 {
+		s = s[:len(s)-1]
+	}
+	return s, nil
+}
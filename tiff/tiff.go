@@ -56,12 +56,29 @@ type Tiff struct {
 // reflects the structure and content of the tiff data. The first read from r
 // should be the first byte of the tiff-encoded data and not necessarily the
 // first byte of an os.File object.
+//
+// Decode applies DefaultDecoderOptions; use DecodeWithOptions when r may
+// come from an untrusted source and tighter bounds are wanted.
 func Decode(r ReadAtReaderSeeker) (*Tiff, error) {
+	return DecodeWithOptions(r, DefaultDecoderOptions)
+}
+
+// DecodeWithOptions is Decode with explicit DecoderOptions, to bound the
+// number of tags, IFDs and value bytes Decode is willing to process.
+func DecodeWithOptions(r ReadAtReaderSeeker, opts DecoderOptions) (*Tiff, error) {
 	t := new(Tiff)
 
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, newTiffError("could not determine reader size", err)
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, newTiffError("could not seek to start of tiff data", err)
+	}
+
 	// read byte order
 	bo := make([]byte, 2)
-	_, err := io.ReadFull(r, bo)
+	_, err = io.ReadFull(r, bo)
 	if err != nil {
 		return nil, newTiffError("could not read tiff byte order", err)
 	}
@@ -94,10 +111,23 @@ func Decode(r ReadAtReaderSeeker) (*Tiff, error) {
 		return nil, newTiffError("could not read offset to first IFD", err)
 	}
 
-	// load IFD's
+	// load IFD's, tracking visited offsets to catch cycles in the next-IFD
+	// chain (not just the immediately-adjacent repeat a naive walk would
+	// catch) and bounding the chain's length against opts.
 	var d *Dir
-	prev := offset
+	visited := make(map[int64]struct{})
 	for offset != 0 {
+		if opts.MaxIFDs > 0 && len(visited) >= opts.MaxIFDs {
+			return nil, newTiffError("too many IFDs", nil)
+		}
+		if opts.MaxIFDChainDepth > 0 && len(visited) >= opts.MaxIFDChainDepth {
+			return nil, newTiffError("IFD chain too deep", nil)
+		}
+		if _, seen := visited[offset]; seen {
+			return nil, newTiffError("recursive IFD", nil)
+		}
+		visited[offset] = struct{}{}
+
 		// seek to offset
 		_, err := r.Seek(offset, 0)
 		if err != nil {
@@ -105,7 +135,7 @@ func Decode(r ReadAtReaderSeeker) (*Tiff, error) {
 		}
 
 		// load the dir
-		d, offset, err = DecodeDir(r, t.Order, t.IsBig)
+		d, offset, err = DecodeDirWithOptions(r, t.Order, t.IsBig, opts, size)
 		if err != nil {
 			if e, ok := err.(TiffError); ok && e.Err == io.EOF {
 				// Previous IFD had a pointer outside of the file. Ignore
@@ -114,11 +144,6 @@ func Decode(r ReadAtReaderSeeker) (*Tiff, error) {
 			return nil, err
 		}
 
-		if offset == prev {
-			return nil, newTiffError("recursive IFD", nil)
-		}
-		prev = offset
-
 		t.Dirs = append(t.Dirs, d)
 	}
 
@@ -149,13 +174,27 @@ func (tf *Tiff) String() string {
 // Dir provides access to the parsed content of a tiff Image File Directory (IFD).
 type Dir struct {
 	Tags []*Tag
+	// RawTags holds tags whose field type isn't registered in
+	// DefaultRegistry, preserved as opaque byte blobs rather than discarded.
+	RawTags []*RawTag
 }
 
 // DecodeDir parses a tiff-encoded IFD from r and returns a Dir object.  offset
 // is the offset to the next IFD.  The first read from r should be at the first
 // byte of the IFD. ReadAt offsets should generally be relative to the
 // beginning of the tiff structure (not relative to the beginning of the IFD).
+//
+// DecodeDir applies DefaultDecoderOptions; use DecodeDirWithOptions to
+// decode against untrusted input with tighter bounds.
 func DecodeDir(r ReadAtReader, order binary.ByteOrder, isBigTIFF bool) (d *Dir, offset int64, err error) {
+	return DecodeDirWithOptions(r, order, isBigTIFF, DefaultDecoderOptions, -1)
+}
+
+// DecodeDirWithOptions is DecodeDir with explicit DecoderOptions. size is
+// the total length, in bytes, of the tiff structure r reads from, forwarded
+// to DecodeTagWithOptions for out-of-line value bounds checks; pass -1 if
+// the size isn't known.
+func DecodeDirWithOptions(r ReadAtReader, order binary.ByteOrder, isBigTIFF bool, opts DecoderOptions, size int64) (d *Dir, offset int64, err error) {
 	d = new(Dir)
 
 	// get num of tags in ifd
@@ -174,15 +213,21 @@ func DecodeDir(r ReadAtReader, order binary.ByteOrder, isBigTIFF bool) (d *Dir,
 		nTags = int64(nTagsShort)
 	}
 
+	if opts.MaxTags > 0 && nTags > int64(opts.MaxTags) {
+		return nil, 0, newTiffError("IFD tag count exceeds MaxTags", nil)
+	}
+
 	// load tags
 	for n := 0; n < int(nTags); n++ {
-		t, err := DecodeTag(r, order, isBigTIFF)
-		if err == errUnhandledTagType {
-			continue
-		} else if err != nil {
+		t, raw, err := DecodeTagWithOptions(r, order, isBigTIFF, opts, size)
+		if err != nil {
 			return nil, 0, err
 		}
-		d.Tags = append(d.Tags, t)
+		if raw != nil {
+			d.RawTags = append(d.RawTags, raw)
+		} else {
+			d.Tags = append(d.Tags, t)
+		}
 	}
 
 	// get offset to next ifd
@@ -199,5 +244,8 @@ func (d *Dir) String() string {
 	for _, t := range d.Tags {
 		s += t.String() + ", "
 	}
+	for _, t := range d.RawTags {
+		s += t.String() + ", "
+	}
 	return s + "}"
 }